@@ -1,33 +1,146 @@
 package pool
 
 import (
-	"sync/atomic"
-
+	"context"
 	"sync"
+	"sync/atomic"
 )
 
-// A Limiter limits access to a resource.
+// A Limiter limits access to a resource. Under contention, access is handed
+// out in FIFO order: a caller requesting a large n is queued ahead of later,
+// smaller requests, rather than being forced to keep re-racing a CAS it will
+// lose to a steady stream of small ones.
 type Limiter struct {
 	inuse int64
 	limit int64
-	cond  *sync.Cond
+
+	// waiting is the number of goroutines currently queued in Get or
+	// GetContext. It's checked (lock-free) before the fast path, since a
+	// request may only bypass the queue when nobody is ahead of it.
+	waiting int32
+
+	mu    sync.Mutex
+	queue []*limiterWaiter
 }
 
-// Get blocks until n units are available, and then claims them. n must be
-// non-negative and less than the Limiter's limit.
-func (l *Limiter) Get(n int) {
+// a limiterWaiter is an entry in a Limiter's FIFO queue. ready is buffered so
+// that grant can hand off n without blocking on a waiter that has since been
+// cancelled.
+type limiterWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+func (l *Limiter) checkN(n int) {
 	if n < 0 {
 		panic("cannot Get a negative number")
-	} else if int64(n) > l.limit {
+	} else if int64(n) > atomic.LoadInt64(&l.limit) {
 		panic("cannot Get more than the limit")
 	}
+}
+
+// tryGet attempts to claim n units via a single CAS. It must only be called
+// when the queue is known to be empty; otherwise a request could jump ahead
+// of waiters already in line.
+func (l *Limiter) tryGet(n int64) bool {
 	for {
 		inuse := atomic.LoadInt64(&l.inuse)
-		new := inuse + int64(n)
-		if new <= l.limit && atomic.CompareAndSwapInt64(&l.inuse, inuse, new) {
+		new := inuse + n
+		if new > atomic.LoadInt64(&l.limit) {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&l.inuse, inuse, new) {
+			return true
+		}
+	}
+}
+
+// enqueue appends a waiter to the back of the FIFO queue.
+func (l *Limiter) enqueue(n int) *limiterWaiter {
+	w := &limiterWaiter{n: int64(n), ready: make(chan struct{}, 1)}
+	l.mu.Lock()
+	l.queue = append(l.queue, w)
+	atomic.AddInt32(&l.waiting, 1)
+	l.mu.Unlock()
+	return w
+}
+
+// cancel removes w from the queue if it hasn't been granted yet. If w was
+// granted in the instant before cancellation, its units are returned to the
+// limiter instead of being leaked.
+func (l *Limiter) cancel(w *limiterWaiter) {
+	l.mu.Lock()
+	for i, q := range l.queue {
+		if q == w {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			atomic.AddInt32(&l.waiting, -1)
+			l.mu.Unlock()
+			return
+		}
+	}
+	l.mu.Unlock()
+	select {
+	case <-w.ready:
+		// w was granted right as we gave up; give the units back.
+		l.Put(int(w.n))
+	default:
+	}
+}
+
+// grant processes the queue from the head, handing out capacity to waiters
+// in order, and stops at the first waiter that doesn't yet fit. This is what
+// keeps a large request from being starved: a smaller request behind it in
+// line can never be served first. l.mu must be held.
+func (l *Limiter) grant() {
+	for len(l.queue) > 0 {
+		w := l.queue[0]
+		inuse := atomic.LoadInt64(&l.inuse)
+		if inuse+w.n > atomic.LoadInt64(&l.limit) {
 			return
 		}
-		l.cond.Wait()
+		if !atomic.CompareAndSwapInt64(&l.inuse, inuse, inuse+w.n) {
+			continue
+		}
+		l.queue = l.queue[1:]
+		atomic.AddInt32(&l.waiting, -1)
+		w.ready <- struct{}{}
+	}
+}
+
+// Get blocks until n units are available, and then claims them. n must be
+// non-negative and less than the Limiter's limit.
+func (l *Limiter) Get(n int) {
+	l.checkN(n)
+	if atomic.LoadInt32(&l.waiting) == 0 && l.tryGet(int64(n)) {
+		return
+	}
+	w := l.enqueue(n)
+	<-w.ready
+}
+
+// TryGet attempts to claim n units without blocking. It reports false if n
+// units are not currently available. To preserve FIFO order, TryGet also
+// fails whenever other goroutines are already queued in Get, even if n units
+// happen to be free.
+func (l *Limiter) TryGet(n int) bool {
+	l.checkN(n)
+	return atomic.LoadInt32(&l.waiting) == 0 && l.tryGet(int64(n))
+}
+
+// GetContext behaves like Get, but returns ctx.Err() if ctx is cancelled
+// before n units become available.
+func (l *Limiter) GetContext(ctx context.Context, n int) error {
+	l.checkN(n)
+	if atomic.LoadInt32(&l.waiting) == 0 && l.tryGet(int64(n)) {
+		return nil
+	}
+	w := l.enqueue(n)
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		l.cancel(w)
+		return ctx.Err()
 	}
 }
 
@@ -40,7 +153,32 @@ func (l *Limiter) Put(n int) {
 	if atomic.AddInt64(&l.inuse, int64(-n)) < 0 {
 		panic("inuse cannot be negative")
 	}
-	l.cond.Broadcast()
+	l.mu.Lock()
+	l.grant()
+	l.mu.Unlock()
+}
+
+// SetLimit changes the Limiter's limit, waking queued waiters (in FIFO
+// order) that fit under the new limit.
+func (l *Limiter) SetLimit(newLimit int64) {
+	if newLimit < 0 {
+		panic("limit must be non-negative")
+	}
+	l.mu.Lock()
+	atomic.StoreInt64(&l.limit, newLimit)
+	l.grant()
+	l.mu.Unlock()
+}
+
+// InUse returns the number of units currently claimed from the limiter.
+func (l *Limiter) InUse() int64 {
+	return atomic.LoadInt64(&l.inuse)
+}
+
+// Waiting returns the number of goroutines currently blocked in Get or
+// GetContext.
+func (l *Limiter) Waiting() int {
+	return int(atomic.LoadInt32(&l.waiting))
 }
 
 // NewLimiter returns a Limiter with the supplied limit, which must be non-
@@ -51,7 +189,6 @@ func NewLimiter(limit int64) *Limiter {
 	}
 	return &Limiter{
 		limit: limit,
-		cond:  sync.NewCond(noopLocker{}),
 	}
 }
 
@@ -67,11 +204,46 @@ func (m *MemLimiter) Get(n int) []byte {
 	return make([]byte, n)
 }
 
+// TryGet attempts to allocate a []byte of length and capacity n without
+// blocking. It reports false if n bytes are not currently available.
+func (m *MemLimiter) TryGet(n int) ([]byte, bool) {
+	if !m.l.TryGet(n) {
+		return nil, false
+	}
+	return make([]byte, n), true
+}
+
+// GetContext behaves like Get, but returns ctx.Err() if ctx is cancelled
+// before n bytes become available.
+func (m *MemLimiter) GetContext(ctx context.Context, n int) ([]byte, error) {
+	if err := m.l.GetContext(ctx, n); err != nil {
+		return nil, err
+	}
+	return make([]byte, n), nil
+}
+
 // Put returns len(b) bytes to the limiter.
 func (m *MemLimiter) Put(b []byte) {
 	m.l.Put(len(b))
 }
 
+// SetLimit changes the MemLimiter's limit, waking queued waiters (in FIFO
+// order) that fit under the new limit.
+func (m *MemLimiter) SetLimit(newLimit int64) {
+	m.l.SetLimit(newLimit)
+}
+
+// InUse returns the number of bytes currently claimed from the limiter.
+func (m *MemLimiter) InUse() int64 {
+	return m.l.InUse()
+}
+
+// Waiting returns the number of goroutines currently blocked in Get or
+// GetContext.
+func (m *MemLimiter) Waiting() int {
+	return m.l.Waiting()
+}
+
 // NewMemLimiter returns a MemLimiter that allows up to limit bytes to be
 // allocated at any given time.
 func NewMemLimiter(limit int64) *MemLimiter {
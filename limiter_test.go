@@ -1,6 +1,7 @@
-package mem
+package pool
 
 import (
+	"context"
 	"runtime"
 	"testing"
 	"time"
@@ -66,6 +67,46 @@ func TestMemLimiter(t *testing.T) {
 	}
 }
 
+func TestLimiterTryGetAndGetContext(t *testing.T) {
+	l := NewLimiter(10)
+	l.Get(10)
+
+	if l.TryGet(1) {
+		t.Fatal("TryGet should have failed at the limit")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.GetContext(ctx, 1); err != ctx.Err() {
+		t.Fatalf("expected %v, got %v", ctx.Err(), err)
+	}
+
+	l.Put(1)
+	if !l.TryGet(1) {
+		t.Fatal("TryGet should have succeeded after Put")
+	}
+}
+
+func TestMemLimiterTryGetAndGetContext(t *testing.T) {
+	l := NewMemLimiter(10)
+	got := l.Get(10)
+
+	if _, ok := l.TryGet(1); ok {
+		t.Fatal("TryGet should have failed at the limit")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.GetContext(ctx, 1); err != ctx.Err() {
+		t.Fatalf("expected %v, got %v", ctx.Err(), err)
+	}
+
+	l.Put(got)
+	if _, ok := l.TryGet(1); !ok {
+		t.Fatal("TryGet should have succeeded after Put")
+	}
+}
+
 func TestLimiterConcurrent(t *testing.T) {
 	l := NewLimiter(10)
 
@@ -82,6 +123,81 @@ func TestLimiterConcurrent(t *testing.T) {
 	getAndPut(1000)
 }
 
+func TestLimiterFIFOFairness(t *testing.T) {
+	// limit is one more than the large request alone requires, so that
+	// granting it still leaves enough for the small request behind it.
+	l := NewLimiter(11)
+	l.Get(10) // claim everything but 1 unit
+
+	// a large request queues first
+	bigDone := make(chan struct{})
+	go func() {
+		l.Get(10)
+		close(bigDone)
+	}()
+	for l.Waiting() == 0 {
+		runtime.Gosched()
+	}
+
+	// a small request queues behind it
+	smallDone := make(chan struct{})
+	go func() {
+		l.Get(1)
+		close(smallDone)
+	}()
+	for l.Waiting() < 2 {
+		runtime.Gosched()
+	}
+
+	// returning only 1 unit isn't enough for the large request, and the
+	// small one behind it must not be allowed to jump the queue
+	l.Put(1)
+	select {
+	case <-smallDone:
+		t.Fatal("small Get jumped ahead of the queued large Get")
+	case <-bigDone:
+		t.Fatal("large Get should not have been satisfied by only 1 unit")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// once the rest is returned, both should be served, in order
+	l.Put(9)
+	select {
+	case <-bigDone:
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("large Get should have been served")
+	}
+	select {
+	case <-smallDone:
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("small Get should have been served after the large Get")
+	}
+}
+
+func TestLimiterSetLimit(t *testing.T) {
+	l := NewLimiter(1)
+	l.Get(1)
+
+	done := make(chan struct{})
+	go func() {
+		l.Get(1)
+		close(done)
+	}()
+	for l.Waiting() == 0 {
+		runtime.Gosched()
+	}
+
+	l.SetLimit(2)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("raising the limit should have woken the waiter")
+	}
+	if got := l.InUse(); got != 2 {
+		t.Fatalf("expected InUse() == 2, got %v", got)
+	}
+}
+
 func TestLimiterPanics(t *testing.T) {
 	shouldPanic(t, func() { NewLimiter(-1) })
 	shouldPanic(t, func() { NewLimiter(1).Get(-1) })
@@ -1,16 +1,18 @@
 package mem
 
 import (
-	"sync"
+	"context"
 	"sync/atomic"
 	"unsafe"
+
+	"lukechampine.com/pool"
 )
 
 // A FixedPool is a pool of fixed-size []byte buffers. FixedPools are safe for
 // concurrent use.
 type FixedPool struct {
 	bufs [][]byte
-	cond *sync.Cond
+	wl   pool.WaitList
 }
 
 // NewFixedPool creates a new FixedPool that contains n buffers of the
@@ -25,28 +27,54 @@ func NewFixedPool(n, bufSize int) *FixedPool {
 	}
 	return &FixedPool{
 		bufs: bufs,
-		cond: sync.NewCond(noopLocker{}),
 	}
 }
 
+// tryGet searches for a buffer with len > 0 (i.e. available) and, if found,
+// marks it unavailable and clears its contents before returning it.
+func (p *FixedPool) tryGet() ([]byte, bool) {
+	for i, s := range p.bufs {
+		iHdr := (*uintptrSliceHeader)(unsafe.Pointer(&p.bufs[i]))
+		// try to mark the buffer as unavailable
+		if atomic.CompareAndSwapUintptr(&iHdr.Len, iHdr.Cap, 0) {
+			// clear old contents before returning
+			for j := range s {
+				s[j] = 0
+			}
+			return s, true
+		}
+	}
+	return nil, false
+}
+
 // Get returns one of the buffers in the pool. If no buffers are available,
 // Get blocks. Buffers are cleared before being returned.
 func (p *FixedPool) Get() []byte {
-	// search for a buffer with len > 0 (i.e. available)
 	for {
-		for i, s := range p.bufs {
-			iHdr := (*uintptrSliceHeader)(unsafe.Pointer(&p.bufs[i]))
-			// try to mark the buffer as unavailable
-			if atomic.CompareAndSwapUintptr(&iHdr.Len, iHdr.Cap, 0) {
-				// clear old contents before returning
-				for j := range s {
-					s[j] = 0
-				}
-				return s
-			}
+		if s, ok := p.tryGet(); ok {
+			return s
 		}
 		// no buffers are available, so block until woken up by a call to Put
-		p.cond.Wait()
+		p.wl.Wait(nil)
+	}
+}
+
+// TryGet returns one of the buffers in the pool, without blocking. It
+// reports false if no buffer is currently available.
+func (p *FixedPool) TryGet() ([]byte, bool) {
+	return p.tryGet()
+}
+
+// GetContext behaves like Get, but returns ctx.Err() if ctx is cancelled
+// before a buffer becomes available.
+func (p *FixedPool) GetContext(ctx context.Context) ([]byte, error) {
+	for {
+		if s, ok := p.tryGet(); ok {
+			return s, nil
+		}
+		if err := p.wl.Wait(ctx); err != nil {
+			return nil, err
+		}
 	}
 }
 
@@ -77,20 +105,13 @@ func (p *FixedPool) Put(b []byte) {
 			// mark the buffer as available
 			atomic.StoreUintptr(&iHdr.Len, iHdr.Cap)
 			// if there are blocked Get calls, wake one up
-			p.cond.Signal()
+			p.wl.Signal()
 			return
 		}
 	}
 	panic("Put []byte did not originate in pool")
 }
 
-// noopLocker implements the sync.Locker interface with no-ops. It exists
-// solely to speed up the call to p.cond.Wait.
-type noopLocker struct{}
-
-func (noopLocker) Lock()   {}
-func (noopLocker) Unlock() {}
-
 // uintptrSliceHeader represents the memory layout of a slice. It is identical
 // to reflect.SliceHeader, except that Len and Cap are uintptrs instead of
 // ints. This allows atomic operations on those fields. Unfortunately, it also
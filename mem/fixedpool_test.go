@@ -1,6 +1,7 @@
 package mem
 
 import (
+	"context"
 	"runtime"
 	"testing"
 	"time"
@@ -52,6 +53,47 @@ func TestFixedPool(t *testing.T) {
 	shouldPanic(t, func() { p.Put(make([]byte, 1000)) })
 }
 
+func TestFixedPoolTryGet(t *testing.T) {
+	p := NewFixedPool(1, 1000)
+
+	b, ok := p.TryGet()
+	if !ok {
+		t.Fatal("TryGet should have succeeded")
+	}
+	if _, ok := p.TryGet(); ok {
+		t.Fatal("TryGet should have failed when the pool is empty")
+	}
+	p.Put(b)
+	if _, ok := p.TryGet(); !ok {
+		t.Fatal("TryGet should have succeeded after Put")
+	}
+}
+
+func TestFixedPoolGetContext(t *testing.T) {
+	p := NewFixedPool(1, 1000)
+	b := p.Get()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.GetContext(ctx); err != ctx.Err() {
+		t.Fatalf("expected %v, got %v", ctx.Err(), err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		if _, err := p.GetContext(context.Background()); err != nil {
+			t.Error(err)
+		}
+		close(done)
+	}()
+	p.Put(b)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("GetContext should have returned once a buffer was available")
+	}
+}
+
 func TestFixedPoolConcurrent(t *testing.T) {
 	p := NewFixedPool(10, 1000)
 
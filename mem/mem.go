@@ -0,0 +1,2 @@
+// Package mem is a collection of memory-management utilities.
+package mem
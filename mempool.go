@@ -2,6 +2,7 @@ package pool
 
 import (
 	"bytes"
+	"context"
 	"reflect"
 	"unsafe"
 )
@@ -24,6 +25,26 @@ func (p MemPool) Get() []byte {
 	return p.bufs[p.indices.Get()]
 }
 
+// TryGet returns one of the buffers in the pool without blocking. It reports
+// false if no buffer is currently available.
+func (p MemPool) TryGet() ([]byte, bool) {
+	i, ok := p.indices.TryGet()
+	if !ok {
+		return nil, false
+	}
+	return p.bufs[i], true
+}
+
+// GetContext behaves like Get, but returns ctx.Err() if ctx is cancelled
+// before a buffer becomes available.
+func (p MemPool) GetContext(ctx context.Context) ([]byte, error) {
+	i, err := p.indices.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.bufs[i], nil
+}
+
 // Put returns a buffer to the pool. b must be a buffer that was returned by
 // Get; otherwise, Put panics. However, buffer may be modified or resliced.
 // All that matters is that b point to the same memory location as the
@@ -90,6 +111,26 @@ func (p BufferPool) Get() *bytes.Buffer {
 	return p.bufs[p.indices.Get()]
 }
 
+// TryGet returns one of the buffers in the pool without blocking. It reports
+// false if no buffer is currently available.
+func (p BufferPool) TryGet() (*bytes.Buffer, bool) {
+	i, ok := p.indices.TryGet()
+	if !ok {
+		return nil, false
+	}
+	return p.bufs[i], true
+}
+
+// GetContext behaves like Get, but returns ctx.Err() if ctx is cancelled
+// before a buffer becomes available.
+func (p BufferPool) GetContext(ctx context.Context) (*bytes.Buffer, error) {
+	i, err := p.indices.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.bufs[i], nil
+}
+
 // Put returns a buffer to the pool. b must be a buffer that was returned by
 // Get; otherwise, Put panics. Callers must not modify the contents of a
 // buffer after returning it to the pool with Put.
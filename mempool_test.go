@@ -2,6 +2,7 @@ package pool
 
 import (
 	"bytes"
+	"context"
 	"runtime"
 	"sync"
 	"testing"
@@ -45,6 +46,29 @@ func TestMemPool(t *testing.T) {
 	shouldPanic(t, func() { p.Put(make([]byte, 1000)) })
 }
 
+func TestMemPoolTryGetAndGetContext(t *testing.T) {
+	p := NewMemPool(1, 1000)
+
+	b, ok := p.TryGet()
+	if !ok {
+		t.Fatal("TryGet should have succeeded")
+	}
+	if _, ok := p.TryGet(); ok {
+		t.Fatal("TryGet should have failed when the pool is empty")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.GetContext(ctx); err != ctx.Err() {
+		t.Fatalf("expected %v, got %v", ctx.Err(), err)
+	}
+
+	p.Put(b)
+	if _, err := p.GetContext(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
 func TestMemPoolConcurrent(t *testing.T) {
 	p := NewMemPool(10, 1000)
 
@@ -173,6 +197,29 @@ func TestBufferPool(t *testing.T) {
 	shouldPanic(t, func() { p.Put(new(bytes.Buffer)) })
 }
 
+func TestBufferPoolTryGetAndGetContext(t *testing.T) {
+	p := NewBufferPool(1, 1000)
+
+	b, ok := p.TryGet()
+	if !ok {
+		t.Fatal("TryGet should have succeeded")
+	}
+	if _, ok := p.TryGet(); ok {
+		t.Fatal("TryGet should have failed when the pool is empty")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.GetContext(ctx); err != ctx.Err() {
+		t.Fatalf("expected %v, got %v", ctx.Err(), err)
+	}
+
+	p.Put(b)
+	if _, err := p.GetContext(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
 func TestBufferPoolConcurrent(t *testing.T) {
 	p := NewBufferPool(10, 1000)
 
@@ -2,57 +2,204 @@
 package pool
 
 import (
+	"context"
 	"fmt"
+	"runtime"
 	"sync"
 	"sync/atomic"
 )
 
-// noopLocker implements the sync.Locker interface with no-ops. It exists
-// solely to speed up the methods of sync.Cond.
-type noopLocker struct{}
+// a ring is a mutex-guarded stack of index "hints" -- indices believed to be
+// free. A hint is only ever a performance shortcut: claiming one still
+// requires winning a CAS on IndexPool's authoritative indices slice, so a
+// stale or duplicate hint can never cause an index to be handed out twice.
+type ring struct {
+	mu   sync.Mutex
+	free []int32
+}
+
+func (r *ring) push(i int32) {
+	r.mu.Lock()
+	r.free = append(r.free, i)
+	r.mu.Unlock()
+}
 
-func (noopLocker) Lock()   {}
-func (noopLocker) Unlock() {}
+func (r *ring) pop() (int32, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.free) == 0 {
+		return 0, false
+	}
+	i := r.free[len(r.free)-1]
+	r.free = r.free[:len(r.free)-1]
+	return i, true
+}
 
 // An IndexPool is a pool the holds a set of indices in the range [0, n).
 // These indices are not very useful on their own; typically the IndexPool is
 // used within another struct to create a type-specific resource pool.
 //
+// A naive IndexPool would scan a single shared array on every Get, CASing
+// each slot in turn; under contention, every goroutine in the program ends
+// up fighting over the same cache lines. To avoid that, indices are sharded
+// across a number of local rings, each owning a contiguous range of the
+// index space, mirroring the two-level local/global scheme that makes
+// sync.Pool scale. Go exposes no portable way to ask which P a goroutine is
+// running on, so shard selection is handed off to a sync.Pool: its Get/Put
+// are satisfied from a per-P private slot on the fast path, with no shared
+// counter or CAS, which gives Get/Put the same P-local affinity sync.Pool
+// itself relies on. Get tries the selected ring first, then steals from
+// sibling rings, then falls back to a shared overflow list, and only then
+// falls back to a full scan -- the same algorithm a naive IndexPool would
+// use -- before blocking.
+//
 // IndexPools are safe for concurrent use.
 type IndexPool struct {
-	indices []int32
-	cond    *sync.Cond
+	// NumShards overrides the number of local rings used to shard indices.
+	// If zero (the default), NumShards is runtime.GOMAXPROCS(0). NumShards
+	// must be set, if at all, before the IndexPool is used.
+	NumShards int
+
+	indices []int32 // indices[i] is 0 if i is free, 1 if i is in use
+
+	initOnce  sync.Once
+	shards    []ring
+	overflow  ring
+	shardPool sync.Pool // hands out *shardHandle with P-local affinity
+	shardSeq  uint32    // only touched the first time a P's handle is created
+
+	wl WaitList
+}
+
+// a shardHandle pins its owner to a single shard. Handles are recycled
+// through shardPool rather than a shared round-robin counter, so a
+// goroutine tends to get back the same handle -- and thus the same shard --
+// it used last time, the way sync.Pool itself hands a P back its own items.
+type shardHandle struct {
+	idx int
+}
+
+// init lazily builds the shards, so that callers have a chance to set
+// NumShards after calling NewIndexPool.
+func (p *IndexPool) init() {
+	p.initOnce.Do(func() {
+		n := p.NumShards
+		if n <= 0 {
+			n = runtime.GOMAXPROCS(0)
+		}
+		if n > len(p.indices) {
+			n = len(p.indices)
+		}
+		if n < 1 {
+			n = 1
+		}
+		p.shards = make([]ring, n)
+		chunk := (len(p.indices) + n - 1) / n
+		for s := range p.shards {
+			lo := s * chunk
+			hi := lo + chunk
+			if hi > len(p.indices) {
+				hi = len(p.indices)
+			}
+			for i := lo; i < hi; i++ {
+				p.shards[s].free = append(p.shards[s].free, int32(i))
+			}
+		}
+		p.shardPool.New = func() interface{} {
+			idx := int(atomic.AddUint32(&p.shardSeq, 1)-1) % n
+			return &shardHandle{idx: idx}
+		}
+	})
+}
+
+// shard returns the calling goroutine's shard, borrowed from shardPool. The
+// handle must be returned with releaseShard once the caller is done with it.
+func (p *IndexPool) shard() *shardHandle {
+	return p.shardPool.Get().(*shardHandle)
+}
+
+func (p *IndexPool) releaseShard(h *shardHandle) {
+	p.shardPool.Put(h)
+}
+
+// claim attempts to mark i as in use, returning whether it succeeded.
+func (p *IndexPool) claim(i int32) bool {
+	return atomic.CompareAndSwapInt32(&p.indices[i], 0, 1)
 }
 
 // Get returns an index from the pool, blocking if necessary until one becomes
 // available.
 func (p *IndexPool) Get() int {
+	p.init()
 	for {
-		// search for an available index
-		for i := range p.indices {
-			// try to mark the index as unavailable
-			if atomic.CompareAndSwapInt32(&p.indices[i], 0, 1) {
-				return i
-			}
+		if i, ok := p.tryClaim(); ok {
+			return i
 		}
 		// no indices are available, so block until woken up by a call to Put
-		p.cond.Wait()
+		p.wl.Wait(nil)
+	}
+}
+
+// TryGet returns an index from the pool, without blocking. It reports false
+// if no index is currently available.
+func (p *IndexPool) TryGet() (int, bool) {
+	p.init()
+	return p.tryClaim()
+}
+
+// GetContext behaves like Get, but returns ctx.Err() if ctx is cancelled
+// before an index becomes available.
+func (p *IndexPool) GetContext(ctx context.Context) (int, error) {
+	p.init()
+	for {
+		if i, ok := p.tryClaim(); ok {
+			return i, nil
+		}
+		if err := p.wl.Wait(ctx); err != nil {
+			return 0, err
+		}
 	}
 }
 
+// tryClaim searches the local shard, then sibling shards, then the overflow
+// list, then (as a last resort) every index, for one that can be claimed.
+func (p *IndexPool) tryClaim() (int, bool) {
+	h := p.shard()
+	defer p.releaseShard(h)
+	if i, ok := p.shards[h.idx].pop(); ok && p.claim(i) {
+		return int(i), true
+	}
+	for s := range p.shards {
+		if i, ok := p.shards[s].pop(); ok && p.claim(i) {
+			return int(i), true
+		}
+	}
+	if i, ok := p.overflow.pop(); ok && p.claim(i) {
+		return int(i), true
+	}
+	for i := range p.indices {
+		if p.claim(int32(i)) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
 // Put returns index i to the pool. Put panics if i was already returned to
 // the pool, or if i is larger than the number of indices in the pool.
 func (p *IndexPool) Put(i int) {
+	p.init()
 	if i < 0 || i >= len(p.indices) {
 		panic(fmt.Sprintf("index %v does not belong to the pool [0,%v)", i, len(p.indices)))
-	} else if atomic.LoadInt32(&p.indices[i]) == 0 {
+	}
+	if !atomic.CompareAndSwapInt32(&p.indices[i], 1, 0) {
 		panic(fmt.Sprintf("index %v was already returned to pool", i))
-	} else if i > 0 && i < len(p.indices) {
-		// mark the index as available
-		atomic.StoreInt32(&p.indices[i], 0)
 	}
+	h := p.shard()
+	p.shards[h.idx].push(int32(i))
+	p.releaseShard(h)
 	// if there are blocked Get calls, wake one up
-	p.cond.Signal()
+	p.wl.Signal()
 }
 
 // NewIndexPool creates a new IndexPool that contains indices in the range
@@ -63,6 +210,5 @@ func NewIndexPool(n int) *IndexPool {
 	}
 	return &IndexPool{
 		indices: make([]int32, n),
-		cond:    sync.NewCond(noopLocker{}),
 	}
 }
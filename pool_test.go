@@ -1,6 +1,7 @@
 package pool
 
 import (
+	"context"
 	"runtime"
 	"testing"
 	"time"
@@ -63,6 +64,64 @@ func TestIndexPoolConcurrent(t *testing.T) {
 	getAndPut(1000)
 }
 
+func TestIndexPoolNumShards(t *testing.T) {
+	p := &IndexPool{NumShards: 1}
+	// NewIndexPool would normally allocate p.indices; do it by hand here so
+	// we can set NumShards before the pool is first used.
+	p.indices = make([]int32, 4)
+
+	if len(p.shards) != 0 {
+		t.Fatalf("shards should not be built until first use")
+	}
+	for i := 0; i < 4; i++ {
+		p.Put(p.Get())
+	}
+	if len(p.shards) != 1 {
+		t.Fatalf("expected 1 shard, got %v", len(p.shards))
+	}
+}
+
+func TestIndexPoolTryGet(t *testing.T) {
+	p := NewIndexPool(1)
+
+	i, ok := p.TryGet()
+	if !ok {
+		t.Fatal("TryGet should have succeeded")
+	}
+	if _, ok := p.TryGet(); ok {
+		t.Fatal("TryGet should have failed when the pool is empty")
+	}
+	p.Put(i)
+	if _, ok := p.TryGet(); !ok {
+		t.Fatal("TryGet should have succeeded after Put")
+	}
+}
+
+func TestIndexPoolGetContext(t *testing.T) {
+	p := NewIndexPool(1)
+	i := p.Get()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.GetContext(ctx); err != ctx.Err() {
+		t.Fatalf("expected %v, got %v", ctx.Err(), err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		if _, err := p.GetContext(context.Background()); err != nil {
+			t.Error(err)
+		}
+		close(done)
+	}()
+	p.Put(i)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("GetContext should have returned once an index was available")
+	}
+}
+
 func TestIndexPoolPanics(t *testing.T) {
 	// empty pool
 	shouldPanic(t, func() { NewIndexPool(0) })
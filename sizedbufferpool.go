@@ -0,0 +1,132 @@
+package pool
+
+import "sync/atomic"
+
+// A SizedBufferPool is a pool of []byte buffers grouped into power-of-two
+// size classes ranging from a minimum to a maximum size. Unlike a MemPool,
+// which only serves buffers of a single fixed size, a SizedBufferPool lets
+// callers request buffers of varying sizes; each request is routed to the
+// smallest class that can satisfy it.
+//
+// SizedBufferPools are safe for concurrent use.
+type SizedBufferPool struct {
+	classes []*sizeClass
+	skips   uint64
+}
+
+type sizeClass struct {
+	size int
+	free chan []byte
+	hits uint64
+	miss uint64
+}
+
+// ClassStats reports the Get/Put activity of a single size class.
+type ClassStats struct {
+	Size   int
+	Hits   uint64 // Get calls served by reusing a pooled buffer
+	Misses uint64 // Get calls served by allocating a new buffer for this class
+}
+
+// Stats reports the activity of a SizedBufferPool, broken down by size
+// class, so that callers can tune their class sizes.
+type Stats struct {
+	Classes []ClassStats
+	Skips   uint64 // Get calls for n > maxSize, served by a plain make([]byte, n)
+}
+
+// Get returns a buffer with length n, drawn from the smallest size class
+// whose capacity is at least n. If n exceeds the pool's maximum size, Get
+// falls back to allocating a new buffer directly and records a skip.
+func (p *SizedBufferPool) Get(n int) []byte {
+	for _, c := range p.classes {
+		if c.size < n {
+			continue
+		}
+		select {
+		case b := <-c.free:
+			atomic.AddUint64(&c.hits, 1)
+			return b[:n]
+		default:
+			atomic.AddUint64(&c.miss, 1)
+			return make([]byte, n, c.size)
+		}
+	}
+	atomic.AddUint64(&p.skips, 1)
+	return make([]byte, n)
+}
+
+// Put returns a buffer to the pool. b is routed by cap(b), not len(b), since
+// a buffer may have been resliced after Get returned it; routing on len(b)
+// would misfile a large buffer into a small (or even the wrong) class. If
+// cap(b) doesn't match any class exactly, Put discards the buffer.
+func (p *SizedBufferPool) Put(b []byte) {
+	c := cap(b)
+	for _, class := range p.classes {
+		if class.size == c {
+			select {
+			case class.free <- b[:0]:
+			default:
+				// class is full; drop the buffer
+			}
+			return
+		}
+	}
+	// doesn't belong to any class; drop it
+}
+
+// Stats returns a snapshot of the pool's per-class and overall activity.
+func (p *SizedBufferPool) Stats() Stats {
+	s := Stats{
+		Classes: make([]ClassStats, len(p.classes)),
+		Skips:   atomic.LoadUint64(&p.skips),
+	}
+	for i, c := range p.classes {
+		s.Classes[i] = ClassStats{
+			Size:   c.size,
+			Hits:   atomic.LoadUint64(&c.hits),
+			Misses: atomic.LoadUint64(&c.miss),
+		}
+	}
+	return s
+}
+
+// NewSizedBufferPool creates a new SizedBufferPool with one sub-pool for
+// each power-of-two size from minSize up to maxSize. Each sub-pool starts
+// empty and retains at most perClass buffers, gaining buffers only as they
+// are returned via Put. minSize and maxSize must be positive, and maxSize
+// must be at least minSize; minSize establishes a lower bound on class
+// size, so that small requests (e.g. a 24-byte write) aren't routed into an
+// oversized class.
+func NewSizedBufferPool(perClass, minSize, maxSize int) *SizedBufferPool {
+	if perClass <= 0 {
+		panic("cannot create empty SizedBufferPool")
+	} else if minSize <= 0 || maxSize < minSize {
+		panic("invalid size range for SizedBufferPool")
+	}
+	p := &SizedBufferPool{}
+	for size := nextPow2(minSize); size < maxSize; size *= 2 {
+		p.classes = append(p.classes, &sizeClass{
+			size: size,
+			free: make(chan []byte, perClass),
+		})
+	}
+	// Always add a final class capped at maxSize, even when maxSize isn't a
+	// power of two (or when nextPow2(minSize) already exceeds maxSize);
+	// otherwise a range like (1500, 1500) would produce no classes at all,
+	// and every Get would silently fall through to the skip path.
+	p.classes = append(p.classes, &sizeClass{
+		size: maxSize,
+		free: make(chan []byte, perClass),
+	})
+	return p
+}
+
+// nextPow2 returns the smallest power of two that is >= n.
+func nextPow2(n int) int {
+	p := 1
+	for p < n {
+		p *= 2
+	}
+	return p
+}
@@ -0,0 +1,148 @@
+package pool
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestSizedBufferPool(t *testing.T) {
+	p := NewSizedBufferPool(2, 64, 256)
+
+	// classes should be 64, 128, 256
+	stats := p.Stats()
+	if len(stats.Classes) != 3 {
+		t.Fatalf("expected 3 classes, got %v", len(stats.Classes))
+	}
+	for i, want := range []int{64, 128, 256} {
+		if stats.Classes[i].Size != want {
+			t.Fatalf("class %v: expected size %v, got %v", i, want, stats.Classes[i].Size)
+		}
+	}
+
+	// a small request should be routed to the smallest class, not the
+	// largest -- a 24-byte write should not consume a 256-byte buffer
+	b := p.Get(24)
+	if cap(b) != 64 {
+		t.Fatalf("expected 24-byte Get to use the 64-byte class, got cap %v", cap(b))
+	}
+	p.Put(b)
+	if hits := p.Stats().Classes[0].Hits; hits != 0 {
+		t.Fatalf("expected first Get to miss, got %v hits", hits)
+	}
+
+	// a second Get of the same size should now hit the pooled buffer
+	b = p.Get(24)
+	if cap(b) != 64 || len(b) != 24 {
+		t.Fatalf("expected cap 64 len 24, got cap %v len %v", cap(b), len(b))
+	}
+	if hits := p.Stats().Classes[0].Hits; hits != 1 {
+		t.Fatalf("expected second Get to hit, got %v hits", hits)
+	}
+	p.Put(b)
+
+	// a request larger than maxSize should skip the pool entirely
+	big := p.Get(1000)
+	if len(big) != 1000 {
+		t.Fatalf("expected len 1000, got %v", len(big))
+	}
+	if skips := p.Stats().Skips; skips != 1 {
+		t.Fatalf("expected 1 skip, got %v", skips)
+	}
+}
+
+func TestSizedBufferPoolPutByCapacity(t *testing.T) {
+	p := NewSizedBufferPool(1, 64, 256)
+
+	// Put must key on cap(b), not len(b); a resliced buffer should still
+	// return to the class matching its original capacity.
+	b := p.Get(200) // routed to the 256-byte class
+	b = b[:10]      // len(b) now looks like it belongs to the 64-byte class
+	p.Put(b)
+
+	b2 := p.Get(200)
+	if cap(b2) != 256 {
+		t.Fatalf("expected reused buffer from 256-byte class, got cap %v", cap(b2))
+	}
+	if hits := p.Stats().Classes[2].Hits; hits != 1 {
+		t.Fatalf("expected the 256-byte class to register the hit, got %v", hits)
+	}
+}
+
+func TestSizedBufferPoolNonPow2Range(t *testing.T) {
+	// minSize and maxSize that don't straddle a power-of-two boundary (here,
+	// equal and non-power-of-two) must still yield at least one class; Get
+	// should hit it instead of silently skipping the pool forever.
+	p := NewSizedBufferPool(1, 1500, 1500)
+
+	stats := p.Stats()
+	if len(stats.Classes) != 1 || stats.Classes[0].Size != 1500 {
+		t.Fatalf("expected a single 1500-byte class, got %v", stats.Classes)
+	}
+
+	b := p.Get(1500)
+	p.Put(b)
+	b = p.Get(1500)
+	if cap(b) != 1500 {
+		t.Fatalf("expected cap 1500, got %v", cap(b))
+	}
+	if hits := p.Stats().Classes[0].Hits; hits != 1 {
+		t.Fatalf("expected a hit, got %v", hits)
+	}
+	if skips := p.Stats().Skips; skips != 0 {
+		t.Fatalf("expected no skips, got %v", skips)
+	}
+}
+
+func TestSizedBufferPoolPanics(t *testing.T) {
+	shouldPanic(t, func() { NewSizedBufferPool(0, 1, 1) })
+	shouldPanic(t, func() { NewSizedBufferPool(1, 0, 1) })
+	shouldPanic(t, func() { NewSizedBufferPool(1, 2, 1) })
+}
+
+func TestSizedBufferPoolConcurrent(t *testing.T) {
+	p := NewSizedBufferPool(10, 64, 256)
+
+	getAndPut := func(n int) {
+		for i := 0; i < n; i++ {
+			b := p.Get(24)
+			runtime.Gosched()
+			p.Put(b)
+			b = p.Get(200)
+			runtime.Gosched()
+			p.Put(b)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		go getAndPut(1000)
+	}
+	getAndPut(1000)
+}
+
+func BenchmarkSizedBufferPool(b *testing.B) {
+	p := NewSizedBufferPool(1000, 64, 1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		p.Put(p.Get(1000))
+	}
+}
+
+func BenchmarkSizedBufferPoolContention(b *testing.B) {
+	p := NewSizedBufferPool(1000, 64, 1024)
+	for i := 0; i < 10; i++ {
+		go func() {
+			for j := 0; j < b.N*2; j++ {
+				buf := p.Get(1000)
+				runtime.Gosched()
+				p.Put(buf)
+			}
+		}()
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		p.Put(p.Get(1000))
+	}
+}
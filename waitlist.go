@@ -0,0 +1,69 @@
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// A WaitList manages goroutines blocked waiting for a resource to free up.
+// It serves the same purpose as sync.Cond, but (unlike sync.Cond) a single
+// waiter can be cancelled without disturbing the others, which is what lets
+// GetContext honor context cancellation. It is exported so that other
+// packages in this module (e.g. mem) can share the same implementation
+// instead of maintaining their own copy.
+type WaitList struct {
+	mu      sync.Mutex
+	waiters []chan struct{}
+}
+
+// Wait blocks until signaled, or until ctx is done. A nil ctx blocks
+// indefinitely.
+func (w *WaitList) Wait(ctx context.Context) error {
+	ch := make(chan struct{}, 1)
+	w.mu.Lock()
+	w.waiters = append(w.waiters, ch)
+	w.mu.Unlock()
+
+	if ctx == nil {
+		<-ch
+		return nil
+	}
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		w.remove(ch)
+		// We may have been signaled in the instant before we gave up. If so,
+		// don't let the wakeup go to waste -- pass it on to another waiter.
+		select {
+		case <-ch:
+			w.Signal()
+		default:
+		}
+		return ctx.Err()
+	}
+}
+
+func (w *WaitList) remove(ch chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, c := range w.waiters {
+		if c == ch {
+			w.waiters = append(w.waiters[:i], w.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// Signal wakes at most one waiter.
+func (w *WaitList) Signal() {
+	w.mu.Lock()
+	if len(w.waiters) == 0 {
+		w.mu.Unlock()
+		return
+	}
+	ch := w.waiters[0]
+	w.waiters = w.waiters[1:]
+	w.mu.Unlock()
+	ch <- struct{}{}
+}